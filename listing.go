@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ================= BUCKET LISTING =================
+
+// ListBucketResult mirrors the subset of the S3 ListObjects (v1) XML
+// response that s3-checker cares about.
+type ListBucketResult struct {
+	XMLName        xml.Name       `xml:"ListBucketResult"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Marker         string         `xml:"Marker"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []S3Object     `xml:"Contents"`
+	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type S3Object struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+type CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// ObjectRecord is the JSONL record written to <output>.objects.jsonl for
+// every object discovered while listing a bucket.
+type ObjectRecord struct {
+	Bucket       string `json:"bucket"`
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"last_modified"`
+}
+
+// BucketListing holds everything collected while paginating a bucket.
+type BucketListing struct {
+	Bucket    string
+	Objects   []S3Object
+	Truncated bool // hit maxKeys before IsTruncated went false
+}
+
+// listBucketObjects performs an anonymous GET against the bucket's listing
+// endpoint and paginates on Marker until IsTruncated is false or maxKeys
+// objects have been collected, respecting the shared rate limiter.
+func listBucketObjects(bucket string, maxKeys int, rate <-chan time.Time) (*BucketListing, error) {
+	listing := &BucketListing{Bucket: bucket}
+	marker := ""
+
+	for {
+		if rate != nil {
+			<-rate
+		}
+
+		reqURL := fmt.Sprintf("http://%s.s3.amazonaws.com/?prefix=&marker=%s", bucket, url.QueryEscape(marker))
+		resp, err := client.Get(reqURL)
+		if err != nil {
+			return listing, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return listing, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return listing, fmt.Errorf("listing %s: unexpected status %d", bucket, resp.StatusCode)
+		}
+
+		var result ListBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return listing, err
+		}
+
+		listing.Objects = append(listing.Objects, result.Contents...)
+
+		if len(listing.Objects) >= maxKeys {
+			listing.Objects = listing.Objects[:maxKeys]
+			listing.Truncated = true
+			break
+		}
+
+		if !result.IsTruncated || len(result.Contents) == 0 {
+			break
+		}
+
+		marker = result.Contents[len(result.Contents)-1].Key
+	}
+
+	return listing, nil
+}
+
+// summarizeListing renders a one-line summary: object count, total size,
+// and the most common file extensions.
+func summarizeListing(listing *BucketListing) string {
+	var total int64
+	extCounts := make(map[string]int)
+
+	for _, obj := range listing.Objects {
+		total += obj.Size
+		ext := strings.ToLower(path.Ext(obj.Key))
+		if ext == "" {
+			ext = "(none)"
+		}
+		extCounts[ext]++
+	}
+
+	type extCount struct {
+		ext   string
+		count int
+	}
+	var exts []extCount
+	for ext, count := range extCounts {
+		exts = append(exts, extCount{ext, count})
+	}
+	sort.Slice(exts, func(i, j int) bool { return exts[i].count > exts[j].count })
+
+	if len(exts) > 3 {
+		exts = exts[:3]
+	}
+	var topStrs []string
+	for _, e := range exts {
+		topStrs = append(topStrs, fmt.Sprintf("%s(%d)", e.ext, e.count))
+	}
+
+	return fmt.Sprintf("%d objects, %s total, top extensions: %s",
+		len(listing.Objects), humanSize(total), strings.Join(topStrs, ", "))
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for n/div >= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ObjectsWriter appends JSONL object records to the companion objects file.
+// It's safe for concurrent use by the worker pool, mirroring the mutex
+// ResultWriter (output.go) uses for its own shared-writer concern.
+type ObjectsWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// newObjectsWriter wraps f, which may be nil when no objects file was
+// requested.
+func newObjectsWriter(f *os.File) *ObjectsWriter {
+	return &ObjectsWriter{f: f}
+}
+
+// WriteListing appends one JSON record per object in listing to the
+// objects file.
+func (ow *ObjectsWriter) WriteListing(listing *BucketListing) error {
+	if ow == nil || ow.f == nil {
+		return nil
+	}
+
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+
+	enc := json.NewEncoder(ow.f)
+	for _, obj := range listing.Objects {
+		rec := ObjectRecord{
+			Bucket:       listing.Bucket,
+			Key:          obj.Key,
+			Size:         obj.Size,
+			LastModified: obj.LastModified,
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
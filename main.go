@@ -3,13 +3,10 @@ package main
 import (
 	"bufio"
 	_ "embed"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -135,17 +132,17 @@ func printBanner(target string, total int, concurrency int, rate int) {
 
 // ================= S3 CHECK =================
 
-func checkBucket(bucket string) (bool, int, string) {
-	url := fmt.Sprintf("http://%s.s3.amazonaws.com", bucket)
+func checkBucket(provider Provider, bucket string) (bool, int, string, http.Header) {
+	url := provider.CandidateURL(bucket)
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return false, 0, url
+		return false, 0, url, nil
 	}
 	defer resp.Body.Close()
 
-	code := resp.StatusCode
-	return code != 404, code, url
+	exists, code := provider.Classify(resp)
+	return exists, code, url, resp.Header
 }
 
 // ================= WORDLIST =================
@@ -155,123 +152,6 @@ var environments = []string{
 	"staging", "prod", "production", "test",
 }
 
-// GrayHatWarfare API response structure (simplified)
-type ghwResponse struct {
-	Buckets []struct {
-		BucketName string `json:"bucketName"`
-	} `json:"buckets"`
-}
-
-// fetchFromGrayHatWarfare queries the GrayHatWarfare API for buckets matching the target keyword.
-// Requires environment variable GHW_API_KEY to be set.
-func fetchFromGrayHatWarfare(target string) []string {
-	apiKey := os.Getenv("GHW_API_KEY")
-	if apiKey == "" {
-		// No API key, silently skip
-		return []string{}
-	}
-
-	url := fmt.Sprintf("https://buckets.grayhatwarfare.com/api/v1/buckets?access_token=%s&keywords=%s", apiKey, target)
-	resp, err := http.Get(url)
-	if err != nil {
-		// Optionally log error, but we'll just return empty
-		return []string{}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return []string{}
-	}
-
-	var result ghwResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return []string{}
-	}
-
-	var buckets []string
-	for _, b := range result.Buckets {
-		buckets = append(buckets, b.BucketName)
-	}
-	return buckets
-}
-
-// fetchFromOsintSh scrapes bucket names from osint.sh/buckets/ by submitting a search form.
-// Can be disabled by setting environment variable OSINT_SH_DISABLE=1.
-// Note: This is experimental and may break if the site structure changes.
-func fetchFromOsintSh(target string) []string {
-	if os.Getenv("OSINT_SH_DISABLE") == "1" {
-		return []string{}
-	}
-
-	// Prepare form data
-	formData := url.Values{
-		"keyword":   {target},
-		"extension": {""}, // optional, leave empty
-	}
-
-	// Create request
-	req, err := http.NewRequest("POST", "https://osint.sh/buckets/", strings.NewReader(formData.Encode()))
-	if err != nil {
-		return []string{}
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", "s3-checker/"+version)
-
-	// Send request with a timeout (use a separate client to avoid interfering with the global one)
-	scrapeClient := &http.Client{Timeout: 10 * time.Second}
-	resp, err := scrapeClient.Do(req)
-	if err != nil {
-		return []string{}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return []string{}
-	}
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return []string{}
-	}
-
-	// Try to extract bucket names using a regex.
-	// Common patterns: bucket-name, bucket.name, bucket_name, often followed by .s3.amazonaws.com
-	// We'll look for anything that could be a bucket name inside href or text.
-	// A simple but broad regex: matches word characters, dots, hyphens (typical bucket chars)
-	// but we need to avoid matching too much. We'll look for occurrences that are likely bucket names
-	// by checking if they appear near "s3.amazonaws.com" or in a list.
-	// This is heuristic and may need adjustment.
-
-	// First, find all potential bucket names (alphanumeric, dot, hyphen, at least 3 chars)
-	re := regexp.MustCompile(`[a-z0-9][a-z0-9.-]{2,}[a-z0-9]`)
-	potential := re.FindAllString(string(body), -1)
-
-	// Also look specifically for URLs pointing to S3
-	urlRe := regexp.MustCompile(`https?://([a-z0-9][a-z0-9.-]+[a-z0-9])\.s3\.amazonaws\.com`)
-	urlMatches := urlRe.FindAllStringSubmatch(string(body), -1)
-
-	unique := make(map[string]struct{})
-	for _, m := range urlMatches {
-		if len(m) >= 2 {
-			unique[m[1]] = struct{}{}
-		}
-	}
-	for _, name := range potential {
-		// Filter out obviously wrong strings (too long, contains invalid chars)
-		if len(name) > 3 && len(name) < 64 && !strings.Contains(name, "..") && !strings.Contains(name, "--") {
-			unique[name] = struct{}{}
-		}
-	}
-
-	// Convert to slice
-	var result []string
-	for name := range unique {
-		result = append(result, name)
-	}
-	return result
-}
-
 func loadEmbeddedWordlist() []string {
 	var lines []string
 	scanner := bufio.NewScanner(strings.NewReader(embeddedWordlist))
@@ -302,61 +182,50 @@ func loadCustomWordlist(path string) ([]string, error) {
 	return lines, scanner.Err()
 }
 
-func generateWordlist(prefix string, words []string) []string {
-	unique := make(map[string]struct{})
-	unique[prefix] = struct{}{}
-
-	envFormats := []string{
-		"%s-%s-%s",
-		"%s-%s.%s",
-		"%s-%s%s",
-		"%s.%s-%s",
-		"%s.%s.%s",
-	}
-
-	hostFormats := []string{"%s.%s", "%s-%s", "%s%s"}
-
-	for _, word := range words {
-		for _, env := range environments {
-			for _, f := range envFormats {
-				unique[fmt.Sprintf(f, prefix, word, env)] = struct{}{}
-			}
-		}
-	}
-
-	for _, word := range words {
-		for _, f := range hostFormats {
-			unique[fmt.Sprintf(f, prefix, word)] = struct{}{}
-			unique[fmt.Sprintf(f, word, prefix)] = struct{}{}
-		}
-	}
-
-	// Add buckets discovered from online sources
-	for _, b := range fetchFromGrayHatWarfare(prefix) {
-		unique[b] = struct{}{}
-	}
-	for _, b := range fetchFromOsintSh(prefix) {
-		unique[b] = struct{}{}
-	}
+// ================= WORKER =================
 
-	var result []string
-	for k := range unique {
-		result = append(result, k)
-	}
-	return result
+// checkJob pairs a candidate bucket name with the provider it should be
+// probed against, so a single wordlist can fan out across clouds.
+type checkJob struct {
+	Bucket    string
+	Provider  Provider
+	SourceTag string
 }
 
-// ================= WORKER =================
-
-func worker(jobs <-chan string, wg *sync.WaitGroup, outFile *os.File, rate <-chan time.Time) {
+func worker(jobs <-chan checkJob, wg *sync.WaitGroup, target string, fileWriter *ResultWriter, streamWriter *ResultWriter, objectsWriter *ObjectsWriter, maxKeys int, rate <-chan time.Time, awsCreds *AWSCredentials, probeWrite bool) {
 	defer wg.Done()
 
-	for bucket := range jobs {
+	for job := range jobs {
 		if rate != nil {
 			<-rate
 		}
 
-		exists, code, url := checkBucket(bucket)
+		var (
+			exists         bool
+			code           int
+			url            string
+			classification Classification
+			redirectRegion string
+			headers        http.Header
+		)
+
+		if job.Provider.Name() == "aws" && awsCreds != nil {
+			url = fmt.Sprintf("http://%s.s3.amazonaws.com", job.Bucket)
+			result, err := classifyBucketSigned(job.Bucket, awsCreds, probeWrite)
+			if err != nil {
+				atomic.AddUint64(&totalChecks, 1)
+				fmt.Printf("\r[+] Checked: %d", atomic.LoadUint64(&totalChecks))
+				continue
+			}
+			exists = result.Classification != NotFound
+			code = result.HeadStatus
+			classification = result.Classification
+			redirectRegion = result.Region
+			headers = result.Headers
+		} else {
+			exists, code, url, headers = checkBucket(job.Provider, job.Bucket)
+		}
+
 		atomic.AddUint64(&totalChecks, 1)
 
 		fmt.Printf("\r[+] Checked: %d", atomic.LoadUint64(&totalChecks))
@@ -374,19 +243,73 @@ func worker(jobs <-chan string, wg *sync.WaitGroup, outFile *os.File, rate <-cha
 		color := colorStatus(code)
 
 		line := fmt.Sprintf(
-			"%s [%s%d%s] [S3 Bucket Found]",
+			"[%s] %s [%s%d%s] [Bucket Found]",
+			job.Provider.Name(),
 			url,
 			color,
 			code,
 			Reset,
 		)
+		if classification != "" {
+			line += fmt.Sprintf(" [%s]", classification)
+		}
 
 		fmt.Printf("\r%s\n", line)
 
-		if outFile != nil {
-			outFile.WriteString(line + "\n")
+		if fileWriter != nil || streamWriter != nil {
+			rec := Record{
+				Timestamp:      time.Now().UTC().Format(time.RFC3339),
+				Target:         target,
+				Bucket:         job.Bucket,
+				URL:            url,
+				Provider:       job.Provider.Name(),
+				Status:         code,
+				Classification: string(classification),
+				RedirectRegion: redirectRegion,
+				SourceTag:      job.SourceTag,
+			}
+			if headers != nil {
+				if cl, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64); err == nil {
+					rec.ContentLength = cl
+				}
+				rec.ServerHeader = headers.Get("Server")
+				rec.Headers = headerMap(headers)
+			}
+
+			writeFinding(fileWriter, line, rec)
+			writeFinding(streamWriter, line, rec)
 		}
+
+		// Listing is only implemented for AWS S3's XML API for now, and
+		// only worth attempting when we know the bucket is actually
+		// listable (or we didn't classify it and fell back on a bare 200).
+		listable := classification == "" && code == http.StatusOK ||
+			classification == ExistsListable
+		if job.Provider.Name() == "aws" && listable {
+			listing, err := listBucketObjects(job.Bucket, maxKeys, rate)
+			if err != nil {
+				fmt.Printf("\r    [-] listing failed: %v\n", err)
+				continue
+			}
+			fmt.Printf("\r    [+] %s\n", summarizeListing(listing))
+			if err := objectsWriter.WriteListing(listing); err != nil {
+				fmt.Printf("\r    [-] writing object records failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// writeFinding renders a finding to rw in whichever format it was
+// configured with; rw may be nil when that destination is disabled.
+func writeFinding(rw *ResultWriter, line string, rec Record) {
+	if rw == nil {
+		return
 	}
+	if rw.format == FormatText {
+		rw.WriteText(line)
+		return
+	}
+	rw.WriteRecord(rec)
 }
 
 // ================= MAIN =================
@@ -400,6 +323,17 @@ func main() {
 	rateLimit := flag.Int("r", 0, "Rate limit (req/sec)")
 	exclude := flag.String("e", "", "Exclude status codes (comma-separated)")
 	flag.IntVar(&statusFilter, "s", 0, "Filter by status code")
+	maxKeys := flag.Int("max-keys", 1000, "Max objects to list per bucket")
+	providerList := flag.String("provider", "aws", "Providers to probe (comma-separated: aws,gcs,azure,do)")
+	doRegion := flag.String("do-region", "nyc3", "DigitalOcean Spaces region")
+	awsProfile := flag.String("profile", "", "AWS credentials profile (~/.aws/credentials) for signed requests")
+	probeWrite := flag.Bool("probe-write", false, "Attempt an anonymous PutObject to detect world-writable buckets")
+	sourcesFlag := flag.String("sources", "", "Passive discovery sources to query (comma-separated: ghw,crtsh,cc,pdns)")
+	rulesPath := flag.String("rules", "", "Custom permutation rules file (defaults to the embedded ruleset)")
+	printCandidates := flag.Bool("print-candidates", false, "Print the generated candidate list and exit without issuing any HTTP requests")
+	outputFormat := flag.String("of", "text", "Output format: json, jsonl, csv or text")
+	stream := flag.Bool("stream", false, "Also stream findings to stdout as they're found, in -of's format")
+	colorOutput := flag.Bool("color", false, "Keep ANSI color codes in the output file (stripped by default)")
 	showVersion := flag.Bool("v", false, "Show version and exit")
 
 	flag.Parse()
@@ -417,6 +351,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *maxKeys <= 0 {
+		fmt.Println("Error: -max-keys must be positive")
+		os.Exit(1)
+	}
+
 	var words []string
 	var err error
 
@@ -430,11 +369,74 @@ func main() {
 		words = loadEmbeddedWordlist()
 	}
 
-	wordlist := generateWordlist(*target, words)
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		fmt.Println("Error loading rules:", err)
+		os.Exit(1)
+	}
+
+	wordlist := generateWordlist(*target, words, rules)
+
+	if *printCandidates {
+		for _, bucket := range wordlist {
+			fmt.Println(bucket)
+		}
+		return
+	}
+
+	providers := parseProviders(*providerList, *doRegion)
+	if len(providers) == 0 {
+		fmt.Println("No valid providers selected")
+		os.Exit(1)
+	}
+
+	seen := make(map[string]struct{})
+	var candidates []checkJob
+	addCandidate := func(bucket string, provider Provider, sourceTag string) {
+		key := provider.Name() + ":" + bucket
+		if _, dup := seen[key]; dup {
+			return
+		}
+		seen[key] = struct{}{}
+		candidates = append(candidates, checkJob{Bucket: bucket, Provider: provider, SourceTag: sourceTag})
+	}
+
+	for _, provider := range providers {
+		for _, bucket := range wordlist {
+			if provider.Valid(bucket) {
+				addCandidate(bucket, provider, "wordlist")
+			}
+		}
+	}
+
+	passiveSources := parsePassiveSources(*sourcesFlag)
+	if len(passiveSources) > 0 {
+		fmt.Printf("[+] Querying %d passive source(s)...\n", len(passiveSources))
+		for _, c := range fetchPassiveCandidates(passiveSources, *target) {
+			provider, ok := providerByName(c.Provider, *doRegion)
+			if !ok || !provider.Valid(c.Name) {
+				continue
+			}
+			addCandidate(c.Name, provider, c.SourceTag)
+		}
+	}
+
+	awsCreds, signed := loadAWSCredentials(*awsProfile)
+	if signed {
+		fmt.Println("[+] AWS credentials found, using signed requests for classification")
+	}
+
+	format, err := parseOutputFormat(*outputFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-	printBanner(*target, len(wordlist), *concurrency, *rateLimit)
+	printBanner(*target, len(candidates), *concurrency, *rateLimit)
 
 	var outFile *os.File
+	var objectsFile *os.File
+	var fileWriter *ResultWriter
 	if *outputPath != "" {
 		outFile, err = os.Create(*outputPath)
 		if err != nil {
@@ -442,6 +444,22 @@ func main() {
 			os.Exit(1)
 		}
 		defer outFile.Close()
+		fileWriter = newResultWriter(outFile, format, *colorOutput)
+		defer fileWriter.Close()
+
+		objectsFile, err = os.Create(*outputPath + ".objects.jsonl")
+		if err != nil {
+			fmt.Println("Error creating objects file:", err)
+			os.Exit(1)
+		}
+		defer objectsFile.Close()
+	}
+	objectsWriter := newObjectsWriter(objectsFile)
+
+	var streamWriter *ResultWriter
+	if *stream {
+		streamWriter = newResultWriter(os.Stdout, format, *colorOutput)
+		defer streamWriter.Close()
 	}
 
 	var rate <-chan time.Time
@@ -449,16 +467,16 @@ func main() {
 		rate = time.Tick(time.Second / time.Duration(*rateLimit))
 	}
 
-	jobs := make(chan string, *concurrency)
+	jobs := make(chan checkJob, *concurrency)
 	var wg sync.WaitGroup
 
 	for i := 0; i < *concurrency; i++ {
 		wg.Add(1)
-		go worker(jobs, &wg, outFile, rate)
+		go worker(jobs, &wg, *target, fileWriter, streamWriter, objectsWriter, *maxKeys, rate, awsCreds, *probeWrite)
 	}
 
-	for _, bucket := range wordlist {
-		jobs <- bucket
+	for _, job := range candidates {
+		jobs <- job
 	}
 	close(jobs)
 
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ================= STRUCTURED OUTPUT =================
+
+// OutputFormat selects how findings are rendered to the output file and
+// the optional stdout stream.
+type OutputFormat string
+
+const (
+	FormatText  OutputFormat = "text"
+	FormatJSON  OutputFormat = "json"
+	FormatJSONL OutputFormat = "jsonl"
+	FormatCSV   OutputFormat = "csv"
+)
+
+// parseOutputFormat validates the -of flag value, defaulting to text.
+func parseOutputFormat(s string) (OutputFormat, error) {
+	switch OutputFormat(strings.ToLower(s)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatJSONL:
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (want json, jsonl, csv or text)", s)
+	}
+}
+
+// Record is one finding, in the shape written for the json/jsonl/csv
+// formats so the output is composable with jq, ripgrep, or a SIEM.
+type Record struct {
+	Timestamp      string            `json:"timestamp"`
+	Target         string            `json:"target"`
+	Bucket         string            `json:"bucket"`
+	URL            string            `json:"url"`
+	Provider       string            `json:"provider"`
+	Status         int               `json:"status"`
+	Classification string            `json:"classification,omitempty"`
+	RedirectRegion string            `json:"redirect_region,omitempty"`
+	ContentLength  int64             `json:"content_length"`
+	ServerHeader   string            `json:"server_header,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	SourceTag      string            `json:"source_tag,omitempty"`
+}
+
+var csvHeader = []string{
+	"timestamp", "target", "bucket", "url", "provider", "status",
+	"classification", "redirect_region", "content_length", "server_header", "source_tag",
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripANSI(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+// ResultWriter renders findings to one destination (the output file, or
+// the stdout stream) in a single OutputFormat. It's safe for concurrent
+// use by the worker pool.
+type ResultWriter struct {
+	format OutputFormat
+	color  bool
+
+	mu      sync.Mutex
+	w       io.Writer
+	csvW    *csv.Writer
+	jsonBuf []Record
+}
+
+// newResultWriter wraps w for the given format. color controls whether
+// ANSI codes are preserved in WriteText output; it's always stripped for
+// structured formats since they have no notion of color.
+func newResultWriter(w io.Writer, format OutputFormat, color bool) *ResultWriter {
+	rw := &ResultWriter{format: format, w: w, color: color}
+	if format == FormatCSV {
+		rw.csvW = csv.NewWriter(w)
+		rw.csvW.Write(csvHeader)
+		rw.csvW.Flush()
+	}
+	return rw
+}
+
+// WriteText writes the legacy human-readable line, stripping ANSI color
+// codes unless color is enabled.
+func (rw *ResultWriter) WriteText(line string) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if !rw.color {
+		line = stripANSI(line)
+	}
+	_, err := fmt.Fprintln(rw.w, line)
+	return err
+}
+
+// WriteRecord renders rec in the writer's structured format. It must not
+// be called when the writer's format is FormatText.
+func (rw *ResultWriter) WriteRecord(rec Record) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	switch rw.format {
+	case FormatJSONL:
+		return json.NewEncoder(rw.w).Encode(rec)
+	case FormatJSON:
+		rw.jsonBuf = append(rw.jsonBuf, rec)
+		return nil
+	case FormatCSV:
+		row := []string{
+			rec.Timestamp, rec.Target, rec.Bucket, rec.URL, rec.Provider,
+			strconv.Itoa(rec.Status), rec.Classification, rec.RedirectRegion,
+			strconv.FormatInt(rec.ContentLength, 10), rec.ServerHeader, rec.SourceTag,
+		}
+		if err := rw.csvW.Write(row); err != nil {
+			return err
+		}
+		rw.csvW.Flush()
+		return rw.csvW.Error()
+	default:
+		return fmt.Errorf("WriteRecord called with format %q", rw.format)
+	}
+}
+
+// Close flushes buffered output. Only the json format needs this, since a
+// JSON array can't be written incrementally like jsonl/csv can.
+func (rw *ResultWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.format == FormatJSON {
+		enc := json.NewEncoder(rw.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rw.jsonBuf)
+	}
+	return nil
+}
+
+// headerMap copies an http.Header into a flat map[string]string for
+// inclusion in a Record, taking the first value of any repeated header.
+func headerMap(h map[string][]string) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if len(v) > 0 {
+			out[k] = v[0]
+		}
+	}
+	return out
+}
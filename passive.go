@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ================= PASSIVE DISCOVERY =================
+
+// Candidate is a bucket name surfaced by a passive discovery source.
+type Candidate struct {
+	Name      string
+	Provider  string
+	SourceTag string
+}
+
+// PassiveSource is a single passive intelligence feed that can turn a
+// target keyword into a list of bucket name candidates. The candidates are
+// queued for checking as they are, against the provider each one names;
+// they are not expanded by generateWordlist.
+type PassiveSource interface {
+	// Tag is the short identifier used on the -sources flag and in output.
+	Tag() string
+	// Fetch queries the source for candidates matching target.
+	Fetch(ctx context.Context, target string) ([]Candidate, error)
+}
+
+// passiveSourceTimeout bounds how long any single source gets to answer.
+const passiveSourceTimeout = 15 * time.Second
+
+// allPassiveSources is the registry backing the -sources flag.
+func allPassiveSources() map[string]PassiveSource {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	return map[string]PassiveSource{
+		"ghw":   ghwSource{client: httpClient},
+		"crtsh": crtshSource{client: httpClient, resolver: netResolver{}},
+		"cc":    commonCrawlSource{client: httpClient},
+		"pdns":  passiveDNSSource{resolver: netResolver{}},
+	}
+}
+
+// parsePassiveSources turns the comma-separated -sources flag value into a
+// list of PassiveSources. Unknown tags are skipped with a warning.
+func parsePassiveSources(input string) []PassiveSource {
+	if input == "" {
+		return nil
+	}
+
+	registry := allPassiveSources()
+	var sources []PassiveSource
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.TrimSpace(strings.ToLower(tag))
+		if tag == "" {
+			continue
+		}
+		src, ok := registry[tag]
+		if !ok {
+			fmt.Printf("[-] Unknown passive source %q, skipping\n", tag)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// fetchPassiveCandidates queries every source concurrently, each bounded by
+// its own timeout, and merges the results.
+func fetchPassiveCandidates(sources []PassiveSource, target string) []Candidate {
+	var (
+		mu         sync.Mutex
+		candidates []Candidate
+		wg         sync.WaitGroup
+	)
+
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src PassiveSource) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), passiveSourceTimeout)
+			defer cancel()
+
+			found, err := src.Fetch(ctx, target)
+			if err != nil {
+				fmt.Printf("[-] passive source %s failed: %v\n", src.Tag(), err)
+				return
+			}
+
+			mu.Lock()
+			candidates = append(candidates, found...)
+			mu.Unlock()
+		}(src)
+	}
+
+	wg.Wait()
+	return candidates
+}
+
+// fetchJSON issues a GET against url, retrying up to retries times on
+// transport errors or non-200 responses, and decodes the JSON body into
+// out on success.
+func fetchJSON(ctx context.Context, httpClient *http.Client, url string, retries int, out interface{}) error {
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "s3-checker/"+version)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+			continue
+		}
+
+		lastErr = json.NewDecoder(resp.Body).Decode(out)
+		resp.Body.Close()
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// ================= GRAYHATWARFARE =================
+
+// ghwSource queries the GrayHatWarfare API for both buckets and files
+// matching the target keyword, paginating each endpoint. Requires the
+// GHW_API_KEY environment variable.
+type ghwSource struct {
+	client *http.Client
+}
+
+func (ghwSource) Tag() string { return "ghw" }
+
+type ghwBucketsResponse struct {
+	Buckets []struct {
+		BucketName string `json:"bucketName"`
+	} `json:"buckets"`
+}
+
+type ghwFilesResponse struct {
+	Files []struct {
+		Bucket string `json:"bucket"`
+	} `json:"files"`
+}
+
+func (s ghwSource) Fetch(ctx context.Context, target string) ([]Candidate, error) {
+	apiKey := os.Getenv("GHW_API_KEY")
+	if apiKey == "" {
+		return nil, nil
+	}
+
+	const pageSize = 100
+	const maxPages = 5
+
+	var candidates []Candidate
+	for _, endpoint := range []string{"buckets", "files"} {
+		for page := 0; page < maxPages; page++ {
+			url := fmt.Sprintf(
+				"https://buckets.grayhatwarfare.com/api/v1/%s?access_token=%s&keywords=%s&limit=%d&start=%d",
+				endpoint, apiKey, target, pageSize, page*pageSize,
+			)
+
+			names, err := s.fetchPage(ctx, endpoint, url)
+			if err != nil || len(names) == 0 {
+				break
+			}
+			for _, name := range names {
+				candidates = append(candidates, Candidate{Name: name, Provider: "aws", SourceTag: "ghw:" + endpoint})
+			}
+			if len(names) < pageSize {
+				break
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+func (s ghwSource) fetchPage(ctx context.Context, endpoint, url string) ([]string, error) {
+	if endpoint == "buckets" {
+		var resp ghwBucketsResponse
+		if err := fetchJSON(ctx, s.client, url, 1, &resp); err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, b := range resp.Buckets {
+			names = append(names, b.BucketName)
+		}
+		return names, nil
+	}
+
+	var resp ghwFilesResponse
+	if err := fetchJSON(ctx, s.client, url, 1, &resp); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range resp.Files {
+		names = append(names, f.Bucket)
+	}
+	return names, nil
+}
+
+// ================= CERTIFICATE TRANSPARENCY (crt.sh) =================
+
+// crtshSource mines crt.sh's certificate transparency log for hostnames
+// that resolve to a cloud storage bucket.
+type crtshSource struct {
+	client   *http.Client
+	resolver DNSResolver
+}
+
+func (crtshSource) Tag() string { return "crtsh" }
+
+type crtshEntry struct {
+	NameValue string `json:"name_value"`
+}
+
+var bucketHostPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^([a-z0-9.\-]+)\.s3\.amazonaws\.com$`),
+	regexp.MustCompile(`^([a-z0-9.\-]+)\.s3-website-[a-z0-9-]+\.amazonaws\.com$`),
+}
+
+// gcsCNAMETarget is what a custom domain must CNAME to in order to serve a
+// GCS bucket under its own name. crt.sh's name_value field only ever holds
+// bare SANs — never a "storage.googleapis.com/<bucket>" path — so this
+// CNAME cross-reference is the only way certificate transparency data can
+// surface a GCS candidate.
+const gcsCNAMETarget = "c.storage.googleapis.com."
+
+// maxCrtshCNAMEChecks bounds how many non-S3 hostnames get a DNS lookup
+// per crt.sh query, since a popular domain can have thousands of SANs.
+const maxCrtshCNAMEChecks = 50
+
+func (s crtshSource) Fetch(ctx context.Context, target string) ([]Candidate, error) {
+	url := fmt.Sprintf("https://crt.sh/?q=%%25%s%%25&output=json", target)
+
+	var entries []crtshEntry
+	if err := fetchJSON(ctx, s.client, url, 1, &entries); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var candidates []Candidate
+	var cnameChecks []string
+
+	for _, e := range entries {
+		for _, host := range strings.Split(e.NameValue, "\n") {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host == "" || strings.Contains(host, "*") {
+				continue
+			}
+
+			matched := false
+			for _, re := range bucketHostPatterns {
+				m := re.FindStringSubmatch(host)
+				if len(m) != 2 {
+					continue
+				}
+				matched = true
+				if _, dup := seen[m[1]]; !dup {
+					seen[m[1]] = struct{}{}
+					candidates = append(candidates, Candidate{Name: m[1], Provider: "aws", SourceTag: "crtsh"})
+				}
+			}
+
+			if !matched {
+				if _, dup := seen[host]; !dup && len(cnameChecks) < maxCrtshCNAMEChecks {
+					seen[host] = struct{}{}
+					cnameChecks = append(cnameChecks, host)
+				}
+			}
+		}
+	}
+
+	for _, host := range cnameChecks {
+		cname, err := s.resolver.LookupCNAME(ctx, host)
+		if err != nil || strings.TrimSuffix(strings.ToLower(cname), ".") != strings.TrimSuffix(gcsCNAMETarget, ".") {
+			continue
+		}
+		candidates = append(candidates, Candidate{Name: host, Provider: "gcs", SourceTag: "crtsh"})
+	}
+
+	return candidates, nil
+}
+
+// ================= COMMON CRAWL =================
+
+// commonCrawlSource queries the Common Crawl CDX index for archived URLs
+// under *.s3.amazonaws.com that mention the target.
+type commonCrawlSource struct {
+	client *http.Client
+}
+
+func (commonCrawlSource) Tag() string { return "cc" }
+
+type cdxEntry struct {
+	URL string `json:"url"`
+}
+
+// ccCollInfo is one entry of collinfo.json, the Common Crawl collection
+// index. Entries are listed newest first; there's no "latest" alias for
+// the dated CDX index ids (e.g. CC-MAIN-2024-33-index), so this has to be
+// fetched before querying the CDX API itself.
+type ccCollInfo struct {
+	ID string `json:"id"`
+}
+
+var (
+	ccIndexMu sync.Mutex
+	ccIndexID string
+)
+
+// resolveCommonCrawlIndex fetches the current CDX index id, caching only a
+// successful result for the lifetime of the process — a transient failure
+// (timeout, 5xx, rate limit) is retried on the next call rather than
+// wedging the source permanently.
+func resolveCommonCrawlIndex(ctx context.Context, httpClient *http.Client) (string, error) {
+	ccIndexMu.Lock()
+	defer ccIndexMu.Unlock()
+
+	if ccIndexID != "" {
+		return ccIndexID, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://index.commoncrawl.org/collinfo.json", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "s3-checker/"+version)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("commoncrawl: collinfo.json returned status %d", resp.StatusCode)
+	}
+
+	var infos []ccCollInfo
+	if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+		return "", err
+	}
+	if len(infos) == 0 {
+		return "", fmt.Errorf("commoncrawl: collinfo.json listed no indexes")
+	}
+
+	ccIndexID = infos[0].ID
+	return ccIndexID, nil
+}
+
+var ccBucketURLRe = regexp.MustCompile(`https?://([a-z0-9.\-]+)\.s3\.amazonaws\.com`)
+
+func (s commonCrawlSource) Fetch(ctx context.Context, target string) ([]Candidate, error) {
+	indexID, err := resolveCommonCrawlIndex(ctx, s.client)
+	if err != nil {
+		return nil, fmt.Errorf("commoncrawl: resolving current index: %w", err)
+	}
+
+	url := fmt.Sprintf(
+		"https://index.commoncrawl.org/%s?url=*.s3.amazonaws.com/*%s*&output=json",
+		indexID, target,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "s3-checker/"+version)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("commoncrawl: unexpected status %d", resp.StatusCode)
+	}
+
+	// The CDX API returns newline-delimited JSON, one record per line.
+	seen := make(map[string]struct{})
+	var candidates []Candidate
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var entry cdxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		m := ccBucketURLRe.FindStringSubmatch(entry.URL)
+		if len(m) != 2 {
+			continue
+		}
+		if _, dup := seen[m[1]]; dup {
+			continue
+		}
+		seen[m[1]] = struct{}{}
+		candidates = append(candidates, Candidate{Name: m[1], Provider: "aws", SourceTag: "cc"})
+	}
+	return candidates, scanner.Err()
+}
+
+// ================= PASSIVE DNS =================
+
+// DNSResolver is the pluggable lookup behind passiveDNSSource, satisfied by
+// netResolver in production and easily swapped for a third-party passive
+// DNS API or a test double.
+type DNSResolver interface {
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+type netResolver struct{}
+
+func (netResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	return net.DefaultResolver.LookupCNAME(ctx, host)
+}
+
+// passiveDNSSource checks plausible customer-owned hostnames under target
+// (env- and purpose-prefixed subdomains, e.g. "prod.target.com") for a CNAME
+// into S3 or GCS, without ever issuing an HTTP request. This is the same
+// CNAME cross-reference crtshSource performs against crt.sh-sourced
+// hostnames, just driven by guesses instead of certificate transparency
+// data — querying the guessed bucket's own s3.amazonaws.com hostname would
+// have no DNS signal to extract, since bucket existence is resolved at the
+// HTTP layer, not DNS.
+type passiveDNSSource struct {
+	resolver DNSResolver
+}
+
+func (passiveDNSSource) Tag() string { return "pdns" }
+
+// passiveDNSHosts builds the list of plausible customer-owned hostnames to
+// check for target, reusing the same environment and purpose prefixes the
+// permutation engine (rules.go) already considers plausible for
+// bucket-backed hosting.
+func passiveDNSHosts(target string) []string {
+	hosts := []string{target}
+	for _, env := range environments {
+		hosts = append(hosts, env+"."+target)
+	}
+	for _, suffix := range ruleSuffixes {
+		hosts = append(hosts, suffix+"."+target)
+	}
+	return hosts
+}
+
+func (s passiveDNSSource) Fetch(ctx context.Context, target string) ([]Candidate, error) {
+	var candidates []Candidate
+
+	for _, host := range passiveDNSHosts(target) {
+		cname, err := s.resolver.LookupCNAME(ctx, host)
+		if err != nil || cname == "" {
+			continue
+		}
+		cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+
+		if cname == strings.TrimSuffix(gcsCNAMETarget, ".") {
+			candidates = append(candidates, Candidate{Name: host, Provider: "gcs", SourceTag: "pdns"})
+			continue
+		}
+
+		for _, re := range bucketHostPatterns {
+			if m := re.FindStringSubmatch(cname); len(m) == 2 {
+				candidates = append(candidates, Candidate{Name: m[1], Provider: "aws", SourceTag: "pdns"})
+				break
+			}
+		}
+	}
+
+	return candidates, nil
+}
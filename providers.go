@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ================= PROVIDERS =================
+
+// Provider abstracts a single cloud storage backend so a wordlist can be
+// fanned out across multiple clouds in one run.
+type Provider interface {
+	// Name is the short identifier used on the -provider flag and in output.
+	Name() string
+	// CandidateURL builds the probe URL for a given bucket/container name.
+	CandidateURL(bucket string) string
+	// Classify turns an HTTP response into an exists/code verdict.
+	Classify(resp *http.Response) (exists bool, code int)
+	// Valid reports whether name satisfies this provider's naming rules.
+	Valid(name string) bool
+}
+
+var awsBucketRe = regexp.MustCompile(`^[a-z0-9][a-z0-9\-.]{1,61}[a-z0-9]$`)
+
+// AWSProvider probes AWS S3's virtual-hosted-style bucket endpoint.
+type AWSProvider struct{}
+
+func (AWSProvider) Name() string { return "aws" }
+
+func (AWSProvider) CandidateURL(bucket string) string {
+	return fmt.Sprintf("http://%s.s3.amazonaws.com", bucket)
+}
+
+func (AWSProvider) Classify(resp *http.Response) (bool, int) {
+	return resp.StatusCode != http.StatusNotFound, resp.StatusCode
+}
+
+func (AWSProvider) Valid(name string) bool {
+	return awsBucketRe.MatchString(name)
+}
+
+var gcsBucketRe = regexp.MustCompile(`^[a-z0-9][a-z0-9\-_.]{1,61}[a-z0-9]$`)
+
+// GCSProvider probes Google Cloud Storage's bucket object-listing endpoint.
+type GCSProvider struct{}
+
+func (GCSProvider) Name() string { return "gcs" }
+
+func (GCSProvider) CandidateURL(bucket string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s", bucket)
+}
+
+func (GCSProvider) Classify(resp *http.Response) (bool, int) {
+	return resp.StatusCode != http.StatusNotFound, resp.StatusCode
+}
+
+func (GCSProvider) Valid(name string) bool {
+	return gcsBucketRe.MatchString(name)
+}
+
+var azureAccountRe = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+
+// AzureProvider probes an Azure Blob Storage account's anonymous container
+// listing endpoint.
+type AzureProvider struct{}
+
+func (AzureProvider) Name() string { return "azure" }
+
+func (AzureProvider) CandidateURL(bucket string) string {
+	return fmt.Sprintf("https://%s.blob.core.windows.net/?comp=list", bucket)
+}
+
+func (AzureProvider) Classify(resp *http.Response) (bool, int) {
+	// Azure returns 404 when the account name doesn't resolve at all, so
+	// anything else (including a 400 for "no public container") is worth
+	// a closer look.
+	return resp.StatusCode != http.StatusNotFound, resp.StatusCode
+}
+
+func (AzureProvider) Valid(name string) bool {
+	return azureAccountRe.MatchString(name)
+}
+
+// DOProvider probes a DigitalOcean Spaces bucket in a single region.
+type DOProvider struct {
+	Region string
+}
+
+func (p DOProvider) Name() string { return "do" }
+
+func (p DOProvider) CandidateURL(bucket string) string {
+	region := p.Region
+	if region == "" {
+		region = "nyc3"
+	}
+	return fmt.Sprintf("https://%s.%s.digitaloceanspaces.com", bucket, region)
+}
+
+func (DOProvider) Classify(resp *http.Response) (bool, int) {
+	return resp.StatusCode != http.StatusNotFound, resp.StatusCode
+}
+
+func (DOProvider) Valid(name string) bool {
+	return awsBucketRe.MatchString(name) // Spaces follows the same S3-style naming rules
+}
+
+// parseProviders turns the comma-separated -provider flag value into a list
+// of Providers. Unknown names are skipped with a warning.
+func parseProviders(input string, doRegion string) []Provider {
+	if input == "" {
+		input = "aws"
+	}
+
+	var providers []Provider
+	for _, name := range strings.Split(input, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "aws":
+			providers = append(providers, AWSProvider{})
+		case "gcs":
+			providers = append(providers, GCSProvider{})
+		case "azure":
+			providers = append(providers, AzureProvider{})
+		case "do":
+			providers = append(providers, DOProvider{Region: doRegion})
+		default:
+			fmt.Printf("[-] Unknown provider %q, skipping\n", name)
+		}
+	}
+	return providers
+}
+
+// providerByName resolves a single Provider from its short name, as used on
+// Candidate.Provider. Used to route passive-discovery candidates to the
+// right backend without requiring the -provider flag to list it.
+func providerByName(name string, doRegion string) (Provider, bool) {
+	switch strings.ToLower(name) {
+	case "aws":
+		return AWSProvider{}, true
+	case "gcs":
+		return GCSProvider{}, true
+	case "azure":
+		return AzureProvider{}, true
+	case "do":
+		return DOProvider{Region: doRegion}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ================= PERMUTATION ENGINE =================
+
+//go:embed default_rules.txt
+var defaultRules string
+
+var ruleBucketRe = regexp.MustCompile(`^[a-z0-9][a-z0-9\-.]{1,61}[a-z0-9]$`)
+
+// ruleClass is one token a rule template can reference, with the values it
+// expands to; {prefix} and {word} are single-valued classes.
+type ruleClass struct {
+	token  string
+	values []string
+}
+
+var ruleRegions = []string{
+	"us-east-1", "us-west-1", "us-west-2",
+	"eu-west-1", "eu-central-1",
+	"ap-southeast-1", "ap-southeast-2", "ap-northeast-1",
+}
+
+var ruleSeparators = []string{"-", ".", "_"}
+
+var ruleSuffixes = []string{"backup", "assets", "media", "logs", "uploads", "static"}
+
+const ruleYearStart = 2018
+
+// ruleYears spans ruleYearStart through the current year.
+func ruleYears() []string {
+	end := time.Now().Year()
+	years := make([]string, 0, end-ruleYearStart+1)
+	for y := ruleYearStart; y <= end; y++ {
+		years = append(years, strconv.Itoa(y))
+	}
+	return years
+}
+
+// loadRules reads permutation rule templates, one per line, skipping blank
+// lines and #-comments. When path is empty, the embedded default ruleset
+// (default_rules.txt) is used.
+func loadRules(path string) ([]string, error) {
+	var scanner *bufio.Scanner
+
+	if path == "" {
+		scanner = bufio.NewScanner(strings.NewReader(defaultRules))
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner = bufio.NewScanner(f)
+	}
+
+	var rules []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	return rules, scanner.Err()
+}
+
+// expandRule substitutes every token class referenced by rule, for one
+// prefix/word pair, returning every combination it expands to. A token
+// that appears more than once in a rule (e.g. {sep} in
+// "{prefix}{sep}{word}{sep}{year}") takes the same value at every
+// occurrence.
+func expandRule(rule, prefix, word string) []string {
+	classes := []ruleClass{
+		{"prefix", []string{prefix}},
+		{"word", []string{word}},
+		{"env", environments},
+		{"region", ruleRegions},
+		{"year", ruleYears()},
+		{"sep", ruleSeparators},
+		{"suffix", ruleSuffixes},
+	}
+
+	results := []string{rule}
+	for _, class := range classes {
+		placeholder := "{" + class.token + "}"
+		if !strings.Contains(results[0], placeholder) {
+			continue
+		}
+
+		var next []string
+		for _, partial := range results {
+			for _, v := range class.values {
+				next = append(next, strings.ReplaceAll(partial, placeholder, v))
+			}
+		}
+		results = next
+	}
+
+	return results
+}
+
+// generateWordlist expands every rule against every word in the wordlist
+// for the given prefix, keeping only candidates that satisfy the AWS
+// bucket naming rules.
+func generateWordlist(prefix string, words []string, rules []string) []string {
+	unique := map[string]struct{}{}
+	if ruleBucketRe.MatchString(prefix) {
+		unique[prefix] = struct{}{}
+	}
+
+	for _, rule := range rules {
+		for _, word := range words {
+			for _, candidate := range expandRule(rule, prefix, word) {
+				if ruleBucketRe.MatchString(candidate) {
+					unique[candidate] = struct{}{}
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(unique))
+	for c := range unique {
+		result = append(result, c)
+	}
+	return result
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ================= SIGNED CLASSIFICATION =================
+
+// Classification is the positive identification of a bucket's access level,
+// only available once SigV4-signed requests are in play.
+type Classification string
+
+const (
+	NotFound          Classification = "NotFound"
+	ExistsPrivate     Classification = "Exists-Private"
+	ExistsListable    Classification = "Exists-Listable"
+	ExistsPublicRead  Classification = "Exists-Public-Read"
+	ExistsPublicWrite Classification = "Exists-Public-Write"
+)
+
+// SignedResult carries the outcome of a signed classification pass for one
+// bucket, including the region it was ultimately resolved to.
+type SignedResult struct {
+	Classification Classification
+	Region         string
+	HeadStatus     int
+	Headers        http.Header
+}
+
+const defaultSigningRegion = "us-east-1"
+
+// commonProbeKeys are tried as a last resort when probing for anonymous
+// read access on a bucket we couldn't list any keys from.
+var commonProbeKeys = []string{"index.html", "favicon.ico"}
+
+// classifyBucketSigned resolves a bucket's region, then uses signed and
+// unsigned HeadBucket/ListObjectsV2 calls to positively classify its access
+// level. probeWrite additionally attempts an anonymous PutObject with a
+// random key to detect world-writable buckets, cleaning up on success.
+func classifyBucketSigned(bucket string, creds *AWSCredentials, probeWrite bool) (SignedResult, error) {
+	region, headStatus, headers, err := resolveBucketRegion(bucket, creds)
+	if err != nil {
+		return SignedResult{}, err
+	}
+
+	result := SignedResult{Region: region, HeadStatus: headStatus, Headers: headers}
+
+	if headStatus == http.StatusNotFound {
+		result.Classification = NotFound
+		return result, nil
+	}
+
+	anonStatus, _, _ := listObjectsV2(bucket, region, nil)
+	if anonStatus == http.StatusOK {
+		result.Classification = ExistsListable
+	} else {
+		// Our own credentials are only used here to discover a sample key to
+		// probe with — succeeding at this list just means our account has
+		// its own access, which says nothing about public exposure.
+		_, signedKeys, _ := listObjectsV2(bucket, region, creds)
+		if probeAnonymousGetObject(bucket, region, signedKeys) {
+			result.Classification = ExistsPublicRead
+		} else {
+			result.Classification = ExistsPrivate
+		}
+	}
+
+	if probeWrite && probeWorldWritable(bucket, region) {
+		result.Classification = ExistsPublicWrite
+	}
+
+	return result, nil
+}
+
+// resolveBucketRegion issues a signed HeadBucket against the
+// defaultSigningRegion (us-east-1) endpoint and follows the x-amz-bucket-region redirect header to find the
+// bucket's true home region.
+func resolveBucketRegion(bucket string, creds *AWSCredentials) (string, int, http.Header, error) {
+	region := defaultSigningRegion
+
+	for attempt := 0; attempt < 2; attempt++ {
+		status, headers, err := headBucket(bucket, region, creds)
+		if err != nil {
+			return region, 0, nil, err
+		}
+		if redirectRegion := headers.Get("x-amz-bucket-region"); redirectRegion != "" && redirectRegion != region {
+			region = redirectRegion
+			continue
+		}
+		return region, status, headers, nil
+	}
+
+	return region, 0, nil, fmt.Errorf("too many region redirects for %s", bucket)
+}
+
+func headBucket(bucket, region string, creds *AWSCredentials) (int, http.Header, error) {
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucket, region)
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	signSigV4(req, creds, region, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, resp.Header, nil
+}
+
+// listObjectsV2 issues a ListObjectsV2 call against bucket's regional
+// endpoint, signing it with creds when non-nil or leaving it anonymous
+// otherwise, and returns the keys it found on success.
+func listObjectsV2(bucket, region string, creds *AWSCredentials) (int, []string, error) {
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/?list-type=2&max-keys=5", bucket, region)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if creds != nil {
+		signSigV4(req, creds, region, nil)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	var result ListBucketResult
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	var keys []string
+	for _, obj := range result.Contents {
+		keys = append(keys, obj.Key)
+	}
+	return resp.StatusCode, keys, nil
+}
+
+// probeAnonymousGetObject attempts an unauthenticated GET on each of keys
+// (falling back to a few common object names when keys is empty) and
+// reports whether S3 served the object rather than denying access — the
+// actual "Exists-Public-Read" signal, independent of listability.
+func probeAnonymousGetObject(bucket, region string, keys []string) bool {
+	candidates := keys
+	if len(candidates) == 0 {
+		candidates = commonProbeKeys
+	}
+
+	for _, key := range candidates {
+		url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+		resp, err := client.Get(url)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+// probeWorldWritable performs an anonymous PutObject with a random key and,
+// if it succeeds, deletes the object again so the probe leaves no trace.
+func probeWorldWritable(bucket, region string) bool {
+	key := randomProbeKey()
+	putURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+
+	req, err := http.NewRequest(http.MethodPut, putURL, strings.NewReader("s3-checker write probe"))
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false
+	}
+
+	if delReq, err := http.NewRequest(http.MethodDelete, putURL, nil); err == nil {
+		if delResp, err := client.Do(delReq); err == nil {
+			delResp.Body.Close()
+		}
+	}
+	return true
+}
+
+func randomProbeKey() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return "s3-checker-probe-" + hex.EncodeToString(buf)
+}